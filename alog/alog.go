@@ -5,16 +5,43 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"sync/atomic"
-	"time"
 )
 
 type ALogger struct {
-	records  chan []byte
-	buff     bufio.Writer
-	queueLen int32         // amount of items currently awaiting to be written to `records`
+	output io.Writer
+	buff   bufio.Writer
+
+	cur         atomic.Value // *block currently accepting reservations
+	rotMu       sync.Mutex   // serializes block rotation
+	flushCh     chan *block  // full blocks waiting for the flusher goroutine
+	oversizedCh chan []byte  // records too large for a block, written directly
+
+	syncMu sync.Mutex // serializes PrintSync's stage+push against doSync's forceRotate
+	ring   syncRing   // pending PrintSync/Sync callers waiting on the next fsync
+	syncCh chan struct{}
+
+	queueLen int32         // amount of staged records not yet written out by the flusher
 	done     chan struct{} // used for signaling inside `alog.Run()` as well as `inactive`
 	inactive chan struct{}
+
+	drainMu   sync.Mutex
+	drainCond *sync.Cond // broadcast whenever the queue drains to empty
+
+	flushDelayNanos int64 // duration producers batch for before forcing a flush; 0 disables, see defaultFlushDelay
+	timerPending    int32 // 1 while a delayed-flush timer is armed
+
+	metricBytes      int64
+	metricBlocks     int64
+	metricFlushNanos int64
+
+	level  int32        // minimum `Severity` enqueued by Debug/Info/Warn/Error
+	vlevel int32        // default `V()` verbosity threshold
+	vmod   atomic.Value // holds *vmoduleFilter, set via SetVModule
+
+	enc   Encoder
+	encMu sync.RWMutex
 }
 
 var std *ALogger = New(os.Stdout, 16, 1024)
@@ -22,8 +49,8 @@ var std *ALogger = New(os.Stdout, 16, 1024)
 // Reinitializes `alog` with given set of parameters.
 // Parameters:
 // output 		-- output location that satisfies `io.Writer`
-// recordsCount -- amount of records stored in channel simultaneosuly
-//		ready for writing to output
+// recordsCount -- amount of full blocks allowed to queue up for the
+//		background flusher before `Print` starts applying backpressure
 // bufferSize   -- size of underlying buffer used for writing to output
 func Init(output io.Writer, recordsCount, bufferSize int) {
 	std = New(output, recordsCount, bufferSize)
@@ -31,11 +58,17 @@ func Init(output io.Writer, recordsCount, bufferSize int) {
 
 func New(output io.Writer, recordsCount, bufferSize int) *ALogger {
 	al := &ALogger{
-		records:  make(chan []byte),
-		buff:     *bufio.NewWriterSize(output, bufferSize),
-		done:     make(chan struct{}, 1),
-		inactive: make(chan struct{}, 1),
+		output:          output,
+		buff:            *bufio.NewWriterSize(output, bufferSize),
+		flushCh:         make(chan *block, recordsCount),
+		oversizedCh:     make(chan []byte, recordsCount),
+		syncCh:          make(chan struct{}, 1),
+		done:            make(chan struct{}, 1),
+		inactive:        make(chan struct{}, 1),
+		flushDelayNanos: int64(defaultFlushDelay),
 	}
+	al.cur.Store(blockPool.Get().(*block))
+	al.drainCond = sync.NewCond(&al.drainMu)
 	al.inactive <- struct{}{}
 	return al
 }
@@ -56,26 +89,26 @@ func (al *ALogger) write(s []byte) (int, error) {
 // Requires calling `Flush()` or `Finish()` after exiting. Later is preferred.
 func (al *ALogger) Run() error {
 	<-al.inactive
+	defer func() { al.inactive <- struct{}{} }()
 
-	var stow []byte
 	for {
 		select {
 		case <-al.done: // occurs only on call to `alog.Panic()` or `alog.Fatal()`
-			al.inactive <- struct{}{}
 			return nil
-		case r := <-al.records:
-			r = append(stow, r...) // from rough testing calling `bufio.Flush()` would be more expensive
-			if bw, err := al.write(r); err != nil {
-				if err == io.ErrShortWrite {
-					stow = r[bw:]
-				} else {
-					// Errors that are not `io.ErrShortWrite` should be handled outside
-					// of this method. Than `alog` can be restarted by calling `Run()` again.
-					return err
-				}
-			} else {
-				stow = []byte("")
+		case bl := <-al.flushCh:
+			if err := al.flushBlock(bl); err != nil {
+				// Errors that occur here should be handled outside of this
+				// method. Then `alog` can be restarted by calling `Run()` again.
+				return err
 			}
+			al.signalDrained()
+		case b := <-al.oversizedCh:
+			if err := al.flushOversized(b); err != nil {
+				return err
+			}
+			al.signalDrained()
+		case <-al.syncCh:
+			al.doSync()
 		}
 	}
 }
@@ -93,45 +126,54 @@ func (al *ALogger) IsActive() bool {
 
 // Waits until there is nothing left to log.
 // Meant to be deffered to gracefully shut down the application.
-// It's your responsibility to make sure that no more writes to `al.records` will occur.
+// It's your responsibility to make sure that no more writes to the logger will occur.
 // Calling while `Run()` hasn't finished might result in loss of data.
 func (al *ALogger) Finish() {
-	for atomic.LoadInt32(&al.queueLen) != 0 || len(al.records) != 0 {
+	al.forceRotate() // flush out whatever is sitting in the current block
+
+	al.drainMu.Lock()
+	for atomic.LoadInt32(&al.queueLen) != 0 || len(al.flushCh) != 0 || len(al.oversizedCh) != 0 {
+		al.drainCond.Wait()
 	}
-	// To avoid last few records not logging. Seems to not occur with higher events and threads count.
-	// Idk, how to do it without such bandaids.
-	time.Sleep(time.Millisecond * 100)
+	al.drainMu.Unlock()
+
 	al.buff.Flush()
 }
 
+// haltAndDrain stops the `Run()` loop and synchronously flushes anything
+// left staged, including the current partially-filled block. Used by the
+// Fatal/Panic family so the triggering message is guaranteed to land after
+// everything that was logged before it.
+func (al *ALogger) haltAndDrain() {
+	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
+	<-al.inactive
+
+	al.drainFlushCh()
+
+	if cur, ok := al.cur.Load().(*block); ok && atomic.LoadInt32(&cur.written) > 0 {
+		al.flushBlock(cur)
+	}
+}
+
 // Prints log message to `io.Writer` set via `Init()` or to `os.Stdout`
 func (al *ALogger) Print(s ...any) {
-	r := []byte(fmt.Sprint(s...))
-	atomic.AddInt32(&al.queueLen, 1)
-	al.records <- r
-	atomic.AddInt32(&al.queueLen, -1)
+	al.stage([]byte(fmt.Sprint(s...)))
 }
 
 func (al *ALogger) Println(s ...any) {
-	r := []byte(fmt.Sprint(s...) + "\n")
-	atomic.AddInt32(&al.queueLen, 1)
-	al.records <- r
-	atomic.AddInt32(&al.queueLen, -1)
+	al.stage([]byte(fmt.Sprint(s...) + "\n"))
 }
 
 func (al *ALogger) Printf(format string, s ...any) {
-	r := []byte(fmt.Sprintf(format, s...) + "\n")
-	atomic.AddInt32(&al.queueLen, 1)
-	al.records <- r
-	atomic.AddInt32(&al.queueLen, -1)
+	al.stage([]byte(fmt.Sprintf(format, s...) + "\n"))
 }
 
 // If `Run()` is writing makes it finish writing current record.
 // Then writes given message and calls `os.Exit(1)`.
 func (al *ALogger) Fatal(s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprint("fatal: ", r)))
 	al.buff.Flush()
 	os.Exit(1)
@@ -139,8 +181,8 @@ func (al *ALogger) Fatal(s ...any) {
 
 func (al *ALogger) Fatalln(s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprint("fatal: ", r, "\n")))
 	al.buff.Flush()
 	os.Exit(1)
@@ -148,8 +190,8 @@ func (al *ALogger) Fatalln(s ...any) {
 
 func (al *ALogger) Fatalf(format string, s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprintf(format, r)))
 	al.buff.Flush()
 	os.Exit(1)
@@ -158,8 +200,8 @@ func (al *ALogger) Fatalf(format string, s ...any) {
 // Same as `Fatal()` but calls `panic()` instead of `os.Exit(1)`
 func (al *ALogger) Panic(s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprint("panic: ", r)))
 	al.buff.Flush()
 	panic(s)
@@ -167,8 +209,8 @@ func (al *ALogger) Panic(s ...any) {
 
 func (al *ALogger) Panicln(s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprint("panic: ", r, "\n")))
 	al.buff.Flush()
 	panic(s)
@@ -176,8 +218,8 @@ func (al *ALogger) Panicln(s ...any) {
 
 func (al *ALogger) Panicf(format string, s ...any) {
 	r := fmt.Sprint(s...)
-	al.done <- struct{}{} // to make sure that write isn't happening inside `Run()` at the moment
-	<-al.inactive
+	al.haltAndDrain()
+	al.dumpDebuglogs()
 	al.write([]byte(fmt.Sprintf(format, r)))
 	al.buff.Flush()
 	panic(s)
@@ -208,15 +250,15 @@ func Finnish() {
 
 // Prints log message to `io.Writer` set via `Init()` or to `os.Stdout`
 func Print(s ...any) {
-	go std.Print(s...)
+	std.Print(s...)
 }
 
 func Println(s ...any) {
-	go std.Println(s...)
+	std.Println(s...)
 }
 
 func Printf(format string, s ...any) {
-	go std.Printf(format, s...)
+	std.Printf(format, s...)
 }
 
 // If `Run()` is writing makes it finish writing current record.
@@ -0,0 +1,199 @@
+package alog
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// blockSize is the fixed capacity of a single staging block. Producers
+// reserve space in the currently active block with a single atomic add and
+// memcpy their formatted record in place -- no per-record channel send and
+// no per-record allocation on the hot path.
+const blockSize = 32 * 1024
+
+type block struct {
+	buf [blockSize]byte
+	// written is the atomically reserved offset; it overshoots len(buf) once
+	// a reservation overflows the block and stays there until reset, so it
+	// is NOT safe to flush up to -- and a reservation is not a guarantee the
+	// copy it covers has landed.
+	written int32
+	// committed is the total bytes whose copy has actually completed.
+	// flushing must wait for this to catch up to the block's final size
+	// before reading buf, or it races a producer still mid-memcpy.
+	committed int32
+	recCount  int32 // number of records staged into this block
+}
+
+func (b *block) reset() {
+	b.written = 0
+	b.committed = 0
+	b.recCount = 0
+}
+
+var blockPool = sync.Pool{
+	New: func() any { return &block{} },
+}
+
+// Metrics reports cumulative throughput counters for the block flusher.
+type Metrics struct {
+	BytesWritten  int64
+	BlocksFlushed int64
+	FlushNanos    int64 // cumulative time spent inside the flush write call
+}
+
+// Metrics returns a snapshot of the logger's flush counters.
+func (al *ALogger) Metrics() Metrics {
+	return Metrics{
+		BytesWritten:  atomic.LoadInt64(&al.metricBytes),
+		BlocksFlushed: atomic.LoadInt64(&al.metricBlocks),
+		FlushNanos:    atomic.LoadInt64(&al.metricFlushNanos),
+	}
+}
+
+// GetMetrics returns a snapshot of `std`'s flush counters.
+func GetMetrics() Metrics { return std.Metrics() }
+
+// stage reserves space for `b` in the current block and copies it in place.
+// When a reservation overflows the block it hands the full block off to the
+// background flusher and retries against a freshly pooled block. Records
+// larger than a whole block can never fit no matter how many times we
+// retry, so those bypass the block buffer entirely and go straight to the
+// flusher as a one-off write.
+func (al *ALogger) stage(b []byte) {
+	atomic.AddInt32(&al.queueLen, 1)
+
+	if len(b) > blockSize {
+		// Preserve rough ordering with what's already staged: force out
+		// whatever this block holds so far before the oversized record.
+		// An overflowed block (written past len(buf)) is already being
+		// rotated by the producer that overflowed it.
+		cur := al.cur.Load().(*block)
+		if used := atomic.LoadInt32(&cur.written); used <= int32(len(cur.buf)) {
+			al.rotate(cur, used)
+		}
+		al.oversizedCh <- b
+		return
+	}
+
+	for {
+		cur := al.cur.Load().(*block)
+		end := atomic.AddInt32(&cur.written, int32(len(b)))
+		start := end - int32(len(b))
+
+		if end <= int32(len(cur.buf)) {
+			copy(cur.buf[start:end], b)
+			atomic.AddInt32(&cur.committed, int32(len(b)))
+			atomic.AddInt32(&cur.recCount, 1)
+			if end == int32(len(cur.buf)) {
+				al.rotate(cur, end)
+			} else {
+				al.scheduleDelayedFlush()
+			}
+			return
+		}
+		if start >= int32(len(cur.buf)) {
+			// Someone else already overflowed this block and is rotating it;
+			// spin until a fresh block is installed.
+			runtime.Gosched()
+			continue
+		}
+		// We're the producer that crossed the line: this record doesn't fit
+		// at all, so leave the block as-is for whoever published it first,
+		// or publish it ourselves, and retry on a new block. Nothing we
+		// reserved here ever gets copied, so the block's used prefix ends
+		// at `start`, not our `end`.
+		al.rotate(cur, start)
+	}
+}
+
+// flushOversized writes a single record that was too big for the block
+// buffer directly to the output.
+func (al *ALogger) flushOversized(b []byte) error {
+	start := time.Now()
+	_, err := al.write(b)
+	atomic.AddInt64(&al.metricBytes, int64(len(b)))
+	atomic.AddInt64(&al.metricBlocks, 1)
+	atomic.AddInt64(&al.metricFlushNanos, int64(time.Since(start)))
+	atomic.AddInt32(&al.queueLen, -1)
+	if err == nil {
+		al.maybeRotate()
+	}
+	return err
+}
+
+// waitCommitted blocks until every reservation in `b` up to `used` has
+// finished its copy. A reservation (a bump of `written`) only promises a
+// producer a slice of the buffer to write into, not that the write has
+// happened yet, so nothing may read `buf` until `committed` has caught up.
+func waitCommitted(b *block, used int32) {
+	for atomic.LoadInt32(&b.committed) < used {
+		runtime.Gosched()
+	}
+}
+
+// rotate hands `old` off to the flusher and installs a fresh block, unless
+// another producer has already done so. `used` is the offset up to which
+// `old` actually holds copied bytes -- rotate waits for every producer still
+// mid-copy below that point before publishing the block to the flusher.
+func (al *ALogger) rotate(old *block, used int32) {
+	waitCommitted(old, used)
+	al.rotMu.Lock()
+	if al.cur.Load().(*block) == old {
+		al.cur.Store(blockPool.Get().(*block))
+		al.flushCh <- old
+	}
+	al.rotMu.Unlock()
+}
+
+// flushBlock writes a block's staged bytes to the output, updates metrics
+// and returns the block to the pool.
+func (al *ALogger) flushBlock(bl *block) error {
+	// rotate/forceRotate only publish a block once waitCommitted confirms
+	// every reservation up to its used length has copied, so `committed` is
+	// that used length. `written` can run past it: an overflowing
+	// reservation bumps it without ever copying anything.
+	n := atomic.LoadInt32(&bl.committed)
+	recs := atomic.LoadInt32(&bl.recCount)
+
+	start := time.Now()
+	_, err := al.write(bl.buf[:n])
+	atomic.AddInt64(&al.metricBytes, int64(n))
+	atomic.AddInt64(&al.metricBlocks, 1)
+	atomic.AddInt64(&al.metricFlushNanos, int64(time.Since(start)))
+	atomic.AddInt32(&al.queueLen, -recs)
+
+	bl.reset()
+	blockPool.Put(bl)
+	if err == nil {
+		al.maybeRotate()
+	}
+	return err
+}
+
+// rotator is implemented by outputs (e.g. *sink.RotatingFile) that need to
+// rotate themselves at a point the caller knows is safe, rather than in the
+// middle of an arbitrary Write call.
+type rotator interface {
+	Due() bool
+	Rotate() error
+}
+
+// maybeRotate gives the output a chance to rotate right after a whole block
+// (or oversized record) has been written -- the one point flushBlock and
+// flushOversized know for certain no write is in flight. al.buff may still
+// be holding some of what was just written, so it's flushed first; without
+// that, bytes written() but still buffered could end up in the file the
+// output is about to rotate into instead of the one they were meant for.
+func (al *ALogger) maybeRotate() {
+	r, ok := al.output.(rotator)
+	if !ok {
+		return
+	}
+	al.buff.Flush()
+	if r.Due() {
+		r.Rotate()
+	}
+}
@@ -0,0 +1,96 @@
+package alog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStageRotationConcurrent stresses stage()'s block rotation path from
+// many goroutines at once. Run with -race: the interesting bugs here are
+// races between producers reserving space in the same block concurrently
+// with it being rotated out from under them.
+func TestStageRotationConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	al := New(&buf, 64, 4096)
+
+	runDone := make(chan struct{})
+	go func() {
+		_ = al.Run()
+		close(runDone)
+	}()
+
+	const goroutines = 16
+	const perGoroutine = 500
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				al.Println("line", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	al.Finish()
+	al.done <- struct{}{}
+	<-runDone
+
+	if got, want := strings.Count(buf.String(), "\n"), goroutines*perGoroutine; got != want {
+		t.Fatalf("got %d lines, want %d", got, want)
+	}
+}
+
+// TestStageOversizedBypassesBlock guards against records larger than
+// blockSize looping forever in stage(): the overflow-retry branch always
+// re-fires on a fresh block since a fresh block is still smaller than the
+// oversized record, so such records must bypass the block buffer entirely.
+func TestStageOversizedBypassesBlock(t *testing.T) {
+	var buf bytes.Buffer
+	al := New(&buf, 64, 4096)
+
+	runDone := make(chan struct{})
+	go func() {
+		_ = al.Run()
+		close(runDone)
+	}()
+
+	al.Print(strings.Repeat("x", blockSize+1))
+
+	al.Finish()
+	al.done <- struct{}{}
+	<-runDone
+
+	if got, want := buf.Len(), blockSize+1; got != want {
+		t.Fatalf("got %d bytes written, want %d", got, want)
+	}
+}
+
+// TestWaitCommittedBlocksUntilCopyLands guards against rotate/flushBlock
+// reading a block's buf while a producer that reserved space in it is still
+// mid-copy: written is bumped by reservation alone, so waitCommitted must
+// not return until committed (bumped only after the memcpy) catches up.
+func TestWaitCommittedBlocksUntilCopyLands(t *testing.T) {
+	b := &block{}
+	atomic.StoreInt32(&b.written, 10)
+
+	copyDone := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&b.committed, 10)
+		close(copyDone)
+	}()
+
+	start := time.Now()
+	waitCommitted(b, 10)
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("waitCommitted returned after %v, before the in-flight copy finished", elapsed)
+	}
+	<-copyDone
+}
@@ -0,0 +1,277 @@
+package alog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Debuglog is a low-overhead "flight recorder": a set of fixed-size ring
+// buffers, one per goroutine, that records are appended to using only
+// atomic operations -- no channel send and no formatting; the only
+// allocation is the PC slice a `CapturedStack` argument records. It is
+// meant for detail that's too hot to push through the normal async pipeline
+// but still worth having around if the process crashes, even if that
+// pipeline was backlogged at the time.
+//
+// On `Fatal`/`Panic` every live ring is decoded and dumped to the output
+// after the regular queue has been drained.
+
+const (
+	dlogRingSize = 256 // records kept per goroutine before older ones are overwritten
+	dlogMaxArgs  = 6
+)
+
+type argKind uint8
+
+const (
+	kindInt argKind = iota
+	kindUint
+	kindString
+	kindHex
+	kindPointer
+	kindStack
+	kindOther // any other type, kept as-is and rendered with fmt.Sprint on dump
+)
+
+// Hex wraps an integer so Debuglog renders it in hexadecimal.
+type Hex int64
+
+// Pointer wraps a uintptr so Debuglog renders it as a pointer.
+type Pointer uintptr
+
+// CapturedStack, when passed to Debuglog, records the caller's stack at
+// that point instead of a value.
+type CapturedStack struct{}
+
+type dlogArg struct {
+	kind  argKind
+	ival  int64
+	sval  string
+	stack []uintptr
+	other any
+}
+
+func toArg(v any) dlogArg {
+	switch x := v.(type) {
+	case Hex:
+		return dlogArg{kind: kindHex, ival: int64(x)}
+	case Pointer:
+		return dlogArg{kind: kindPointer, ival: int64(x)}
+	case CapturedStack:
+		pcs := make([]uintptr, 16)
+		n := runtime.Callers(3, pcs)
+		return dlogArg{kind: kindStack, stack: pcs[:n]}
+	case string:
+		return dlogArg{kind: kindString, sval: x}
+	case int:
+		return dlogArg{kind: kindInt, ival: int64(x)}
+	case int64:
+		return dlogArg{kind: kindInt, ival: x}
+	case uint:
+		return dlogArg{kind: kindUint, ival: int64(x)}
+	case uint64:
+		return dlogArg{kind: kindUint, ival: int64(x)}
+	default:
+		return dlogArg{kind: kindOther, other: x}
+	}
+}
+
+func (a dlogArg) decode() string {
+	switch a.kind {
+	case kindHex:
+		return "0x" + strconv.FormatInt(a.ival, 16)
+	case kindPointer:
+		return fmt.Sprintf("%#x", uintptr(a.ival))
+	case kindStack:
+		var b strings.Builder
+		frames := runtime.CallersFrames(a.stack)
+		for {
+			fr, more := frames.Next()
+			fmt.Fprintf(&b, "\n\t%s:%d %s", fr.File, fr.Line, fr.Function)
+			if !more {
+				break
+			}
+		}
+		return b.String()
+	case kindUint:
+		return strconv.FormatUint(uint64(a.ival), 10)
+	case kindString:
+		return a.sval
+	case kindOther:
+		return fmt.Sprint(a.other)
+	default:
+		return strconv.FormatInt(a.ival, 10)
+	}
+}
+
+// dlogEntry is one recorded event. Only `pc` is kept for the call site;
+// the file/line/function lookup happens on dump, never on the hot path.
+type dlogEntry struct {
+	nanotime int64
+	pc       uintptr
+	nargs    int
+	args     [dlogMaxArgs]dlogArg
+}
+
+func (e *dlogEntry) decode() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]", time.Unix(0, e.nanotime).Format(time.RFC3339Nano))
+	if fn := runtime.FuncForPC(e.pc); fn != nil {
+		file, line := fn.FileLine(e.pc)
+		fmt.Fprintf(&b, " %s:%d", file, line)
+	}
+	for i := 0; i < e.nargs; i++ {
+		b.WriteByte(' ')
+		b.WriteString(e.args[i].decode())
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// dlogRing is a single-writer, multi-reader ring buffer: only the goroutine
+// that owns it ever appends, so appends need no locking, only atomics to
+// make the write visible to whatever goroutine eventually dumps it.
+type dlogRing struct {
+	seq   uint64 // atomic; total records ever appended
+	slots [dlogRingSize]dlogEntry
+}
+
+func (r *dlogRing) append(pc uintptr, args []any) {
+	if atomic.LoadInt32(&dlogFrozen) != 0 {
+		// A dump is in progress; drop the record instead of racing the
+		// dumper over a slot it may currently be reading.
+		return
+	}
+	// Only this ring's owning goroutine ever appends, so the write index is
+	// safe to read non-atomically; `seq` is published with an atomic store
+	// only once the entry is fully populated, so a concurrent dump that
+	// loads `seq` never observes a half-written entry.
+	idx := atomic.LoadUint64(&r.seq)
+	e := &r.slots[idx%dlogRingSize]
+	e.nanotime = time.Now().UnixNano()
+	e.pc = pc
+	e.nargs = 0
+	for _, a := range args {
+		if e.nargs >= dlogMaxArgs {
+			break
+		}
+		e.args[e.nargs] = toArg(a)
+		e.nargs++
+	}
+	atomic.StoreUint64(&r.seq, idx+1)
+}
+
+// dump renders entries oldest-first.
+func (r *dlogRing) dump(w *strings.Builder) {
+	seq := atomic.LoadUint64(&r.seq)
+	// Once the ring has wrapped, the slot an in-flight append rewrites is
+	// the oldest one in range; leave it out so it is never read half-written.
+	count := uint64(dlogRingSize - 1)
+	if seq < count {
+		count = seq
+	}
+	start := seq - count
+	for i := start; i < seq; i++ {
+		w.WriteString(r.slots[i%dlogRingSize].decode())
+	}
+}
+
+// dlogFrozen is set while a dump is in progress so that `append` stops
+// writing instead of racing the dumper over a ring slot it may be reading.
+// There is no way to truly stop arbitrary goroutines from user code, so this
+// is best-effort: it closes the window for everything but a writer already
+// past the check when the dump starts, which `append`'s populate-then-
+// publish ordering keeps from exposing a half-written entry either way.
+var dlogFrozen int32
+
+// dlogRegistry and dlogByGoroutine both hold every ring ever created for the
+// life of the process: a ring is cached on first use by its owning
+// goroutine's id and never removed, since Go gives us no hook for "this
+// goroutine has exited" to reclaim it on. For a flight recorder whose whole
+// job is to still have something to dump whenever the process eventually
+// crashes, that's the right trade-off against a bounded cache that might
+// evict the one ring that mattered -- but it does mean memory here grows
+// with the number of *distinct* goroutine ids Debuglog is ever called from,
+// not the number live at once. A workload that churns through goroutines
+// punishes this; it isn't meant for that.
+//
+// It also means a ring can outlive the goroutine that created it: Go
+// recycles goroutine ids, so a new goroutine can land on a dead one's id and
+// `Load` straight into its old records instead of starting fresh. Decoding
+// still produces a readable (if misattributed) trace, which is an
+// acceptable trade for a crash-time debugging aid.
+var dlogRegistry sync.Map // *dlogRing -> struct{}; the set dumpDebuglogs ranges over
+
+var dlogByGoroutine sync.Map // goroutine id (uint64) -> *dlogRing
+
+// goroutineID extracts the numeric id from the "goroutine N [running]:"
+// header line `runtime.Stack` always writes first, ignoring the rest of the
+// stack trace that follows it.
+func goroutineID(stack []byte) uint64 {
+	const prefix = "goroutine "
+	if !bytes.HasPrefix(stack, []byte(prefix)) {
+		return 0
+	}
+	rest := stack[len(prefix):]
+	end := bytes.IndexByte(rest, ' ')
+	if end < 0 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(rest[:end]), 10, 64)
+	return id
+}
+
+// dlogLocal returns the calling goroutine's ring, allocating it on first use
+// and caching it for the life of the process (see the dlogRegistry/
+// dlogByGoroutine comment above for what that caching costs). Goroutines
+// have no exported identity in Go, so the numeric id in the stack trace's
+// leading "goroutine N [running]:" line -- stable for the goroutine's
+// lifetime -- stands in for one. The rest of that trace (which varies with
+// the call site) must not leak into the key, or the same goroutine calling
+// from two different places would get two different rings.
+func dlogLocal() *dlogRing {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	key := goroutineID(buf[:n])
+
+	if r, ok := dlogByGoroutine.Load(key); ok {
+		return r.(*dlogRing)
+	}
+	r, _ := dlogByGoroutine.LoadOrStore(key, &dlogRing{})
+	ring := r.(*dlogRing)
+	dlogRegistry.Store(ring, struct{}{})
+	return ring
+}
+
+// Debuglog appends a record to the calling goroutine's ring buffer. It is
+// safe to call at any rate: there is no channel send and no formatting, so
+// it never blocks on the logger's normal, potentially-backlogged pipeline.
+func Debuglog(args ...any) {
+	pc, _, _, _ := runtime.Caller(1)
+	dlogLocal().append(pc, args)
+}
+
+// dumpDebuglogs decodes every live ring and writes them to the output. Only
+// called from the Fatal/Panic paths, after the regular queue has drained.
+// Freezing all rings first quiesces producers so the dump below isn't torn
+// by a concurrent append landing on a slot it's reading.
+func (al *ALogger) dumpDebuglogs() {
+	atomic.StoreInt32(&dlogFrozen, 1)
+
+	var b strings.Builder
+	dlogRegistry.Range(func(key, _ any) bool {
+		r := key.(*dlogRing)
+		b.WriteString("--- goroutine debuglog ---\n")
+		r.dump(&b)
+		return true
+	})
+	if b.Len() > 0 {
+		al.write([]byte(b.String()))
+	}
+}
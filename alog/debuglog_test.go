@@ -0,0 +1,49 @@
+package alog
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDlogRingDumpOrder checks that dump renders every appended entry,
+// oldest first.
+func TestDlogRingDumpOrder(t *testing.T) {
+	r := &dlogRing{}
+	for i := 0; i < 3; i++ {
+		r.append(0, []any{i})
+	}
+
+	var b strings.Builder
+	r.dump(&b)
+
+	got := b.String()
+	prev := -1
+	for i := 0; i < 3; i++ {
+		idx := strings.Index(got, " "+strconv.Itoa(i)+"\n")
+		if idx < 0 {
+			t.Fatalf("dump missing entry %d, got %q", i, got)
+		}
+		if idx <= prev {
+			t.Fatalf("dump entry %d out of order, got %q", i, got)
+		}
+		prev = idx
+	}
+}
+
+// TestDlogFrozenStopsAppend guards against a dump in progress racing a
+// concurrent append over a ring slot: once dlogFrozen is set, append must be
+// a no-op rather than writing to the ring.
+func TestDlogFrozenStopsAppend(t *testing.T) {
+	old := atomic.LoadInt32(&dlogFrozen)
+	defer atomic.StoreInt32(&dlogFrozen, old)
+
+	r := &dlogRing{}
+	atomic.StoreInt32(&dlogFrozen, 1)
+	r.append(0, []any{"should not land"})
+
+	if seq := atomic.LoadUint64(&r.seq); seq != 0 {
+		t.Fatalf("append wrote to a frozen ring, seq = %d", seq)
+	}
+}
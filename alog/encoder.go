@@ -0,0 +1,124 @@
+package alog
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// record is the in-memory representation of a leveled/structured log entry
+// before it is rendered by an `Encoder`. Caller info is kept as a raw PC so
+// that the (comparatively expensive) file/line/function lookup only happens
+// when the record is actually encoded, not on the hot logging path.
+type record struct {
+	time time.Time
+	sev  Severity
+	msg  string
+	kv   []any
+	pc   uintptr
+}
+
+func (r record) caller() (file string, line int) {
+	if r.pc == 0 {
+		return "", 0
+	}
+	fn := runtime.FuncForPC(r.pc)
+	if fn == nil {
+		return "", 0
+	}
+	return fn.FileLine(r.pc)
+}
+
+// Encoder renders a record into the bytes that get written to the output.
+type Encoder interface {
+	Encode(r record) []byte
+}
+
+// LogfmtEncoder renders records as `key=value` pairs, one record per line.
+// This is the default encoder.
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(r record) []byte {
+	var b strings.Builder
+	file, line := r.caller()
+	fmt.Fprintf(&b, "time=%s level=%s", r.time.Format(time.RFC3339Nano), r.sev)
+	if file != "" {
+		fmt.Fprintf(&b, " caller=%s:%d", file, line)
+	}
+	fmt.Fprintf(&b, " msg=%q", r.msg)
+	for i := 0; i+1 < len(r.kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", r.kv[i], r.kv[i+1])
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// JSONEncoder renders records as single-line JSON objects.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(r record) []byte {
+	var b strings.Builder
+	file, line := r.caller()
+	fmt.Fprintf(&b, `{"time":%q,"level":%q`, r.time.Format(time.RFC3339Nano), r.sev.String())
+	if file != "" {
+		fmt.Fprintf(&b, `,"caller":"%s:%d"`, file, line)
+	}
+	fmt.Fprintf(&b, `,"msg":%q`, r.msg)
+	for i := 0; i+1 < len(r.kv); i += 2 {
+		fmt.Fprintf(&b, `,%q:%q`, fmt.Sprint(r.kv[i]), fmt.Sprint(r.kv[i+1]))
+	}
+	b.WriteByte('}')
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// SetEncoder configures how leveled and structured records are rendered.
+// Plain `Print`/`Println`/`Printf` calls are unaffected.
+func (al *ALogger) SetEncoder(e Encoder) {
+	al.encMu.Lock()
+	al.enc = e
+	al.encMu.Unlock()
+}
+
+func (al *ALogger) encoder() Encoder {
+	al.encMu.RLock()
+	defer al.encMu.RUnlock()
+	if al.enc == nil {
+		return LogfmtEncoder{}
+	}
+	return al.enc
+}
+
+// enqueueRecord records the caller's PC (resolved lazily at encode time,
+// never on this hot path) and stages the rendered record through the same
+// block buffer `Print` uses. `skip` is passed to `runtime.Caller` as is, so
+// it counts up from enqueueRecord's own frame (0) to the frame of whoever
+// called the caller-facing log method.
+func (al *ALogger) enqueueRecord(r record, skip int) {
+	if pc, _, _, ok := runtime.Caller(skip); ok {
+		r.pc = pc
+	}
+	r.time = time.Now()
+	al.stage(al.encoder().Encode(r))
+}
+
+// InfoKV logs a structured message at Info severity with alternating
+// key/value pairs, rendered by the configured `Encoder`.
+func (al *ALogger) InfoKV(msg string, kv ...any) {
+	if !al.enabled(SInfo) {
+		return
+	}
+	al.enqueueRecord(record{sev: SInfo, msg: msg, kv: kv}, 2)
+}
+
+// InfoKV calls `std.enqueueRecord` directly rather than delegating to
+// `std.InfoKV`, for the same reason the leveled package wrappers in
+// level.go do: delegating would add a stack frame and point caller info at
+// this file instead of the real call site.
+func InfoKV(msg string, kv ...any) {
+	if !std.enabled(SInfo) {
+		return
+	}
+	std.enqueueRecord(record{sev: SInfo, msg: msg, kv: kv}, 2)
+}
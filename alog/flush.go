@@ -0,0 +1,64 @@
+package alog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// defaultFlushDelay is the batching window a new *ALogger starts with. At
+// steady, low log volume a block can sit well short of full indefinitely;
+// without a non-zero default here, those records would stay invisible and
+// crash-vulnerable until something else forced a flush, which is the
+// regression this whole mechanism replaced the old busy-loop Finish to
+// avoid.
+const defaultFlushDelay = 100 * time.Millisecond
+
+// SetFlushDelay configures how long the logger batches staged records
+// before forcing a partial block out to the flusher. New loggers start with
+// `defaultFlushDelay`; passing 0 disables delayed flushing entirely, so
+// records only leave once their block fills up or a `Sync`/`Finish` forces
+// them out -- pick that deliberately, since it otherwise silently reverts
+// to "only flush on a full block."
+//
+// Under steady load this coalesces many small writes into one flush per
+// `FlushDelay` window. When idle, no timer is armed and no goroutine spins:
+// the next flush is only scheduled once a producer stages something new.
+func (al *ALogger) SetFlushDelay(d time.Duration) {
+	atomic.StoreInt64(&al.flushDelayNanos, int64(d))
+}
+
+func (al *ALogger) flushDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&al.flushDelayNanos))
+}
+
+// scheduleDelayedFlush arms a one-shot timer that forces the current block
+// out once `FlushDelay` elapses, unless one is already pending. Producers
+// call this after staging; the timer itself only ever reschedules from a
+// producer, never from itself, so an idle logger does no work.
+func (al *ALogger) scheduleDelayedFlush() {
+	d := al.flushDelay()
+	if d <= 0 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&al.timerPending, 0, 1) {
+		return // a flush for the current batch is already scheduled
+	}
+	time.AfterFunc(d, func() {
+		atomic.StoreInt32(&al.timerPending, 0)
+		al.forceRotate()
+	})
+}
+
+// signalDrained wakes any `Finish` callers blocked waiting for the queue to
+// empty, if it in fact just became empty.
+func (al *ALogger) signalDrained() {
+	if atomic.LoadInt32(&al.queueLen) != 0 || len(al.flushCh) != 0 || len(al.oversizedCh) != 0 {
+		return
+	}
+	al.drainMu.Lock()
+	al.drainCond.Broadcast()
+	al.drainMu.Unlock()
+}
+
+// SetFlushDelay configures `std`'s flush batching window.
+func SetFlushDelay(d time.Duration) { std.SetFlushDelay(d) }
@@ -0,0 +1,84 @@
+package alog
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Severity is the level of a log record, ordered from least to most severe.
+type Severity int32
+
+const (
+	SDebug Severity = iota
+	SInfo
+	SWarn
+	SError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SDebug:
+		return "DEBUG"
+	case SInfo:
+		return "INFO"
+	case SWarn:
+		return "WARN"
+	case SError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// SetLevel sets the minimum severity that will be enqueued for logging.
+// Records below this severity are dropped before they are staged.
+func (al *ALogger) SetLevel(s Severity) {
+	atomic.StoreInt32(&al.level, int32(s))
+}
+
+func (al *ALogger) levelThreshold() Severity {
+	return Severity(atomic.LoadInt32(&al.level))
+}
+
+func (al *ALogger) enabled(s Severity) bool {
+	return s >= al.levelThreshold()
+}
+
+func (al *ALogger) logLeveled(s Severity, skip int, msg string) {
+	if !al.enabled(s) {
+		return
+	}
+	al.enqueueRecord(record{sev: s, msg: msg}, skip)
+}
+
+func (al *ALogger) Debug(s ...any) { al.logLeveled(SDebug, 3, fmt.Sprint(s...)) }
+func (al *ALogger) Info(s ...any)  { al.logLeveled(SInfo, 3, fmt.Sprint(s...)) }
+func (al *ALogger) Warn(s ...any)  { al.logLeveled(SWarn, 3, fmt.Sprint(s...)) }
+func (al *ALogger) Error(s ...any) { al.logLeveled(SError, 3, fmt.Sprint(s...)) }
+
+func (al *ALogger) Debugf(format string, s ...any) {
+	al.logLeveled(SDebug, 3, fmt.Sprintf(format, s...))
+}
+func (al *ALogger) Infof(format string, s ...any) { al.logLeveled(SInfo, 3, fmt.Sprintf(format, s...)) }
+func (al *ALogger) Warnf(format string, s ...any) { al.logLeveled(SWarn, 3, fmt.Sprintf(format, s...)) }
+func (al *ALogger) Errorf(format string, s ...any) {
+	al.logLeveled(SError, 3, fmt.Sprintf(format, s...))
+}
+
+// SetLevel sets the minimum severity on `std`.
+func SetLevel(s Severity) { std.SetLevel(s) }
+
+// The package-level wrappers below call `std.logLeveled` directly rather
+// than delegating to the `std.Debug`/`std.Info`/... methods: delegating
+// would add an extra stack frame, throwing off the caller-info `skip`
+// count baked into `logLeveled` and silently pointing `-vmodule` and
+// `caller=` output at this file instead of the real call site.
+func Debug(s ...any) { std.logLeveled(SDebug, 3, fmt.Sprint(s...)) }
+func Info(s ...any)  { std.logLeveled(SInfo, 3, fmt.Sprint(s...)) }
+func Warn(s ...any)  { std.logLeveled(SWarn, 3, fmt.Sprint(s...)) }
+func Error(s ...any) { std.logLeveled(SError, 3, fmt.Sprint(s...)) }
+
+func Debugf(format string, s ...any) { std.logLeveled(SDebug, 3, fmt.Sprintf(format, s...)) }
+func Infof(format string, s ...any)  { std.logLeveled(SInfo, 3, fmt.Sprintf(format, s...)) }
+func Warnf(format string, s ...any)  { std.logLeveled(SWarn, 3, fmt.Sprintf(format, s...)) }
+func Errorf(format string, s ...any) { std.logLeveled(SError, 3, fmt.Sprintf(format, s...)) }
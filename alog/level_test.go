@@ -0,0 +1,69 @@
+package alog
+
+import (
+	"strings"
+	"testing"
+)
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+// TestPackageLevelCallerInfo guards against the package-level wrappers
+// (Info, Debug, ...) delegating through std.Info/std.Debug, which adds an
+// extra stack frame and points caller= at level.go instead of the real call
+// site.
+func TestPackageLevelCallerInfo(t *testing.T) {
+	old := std
+	defer func() { std = old }()
+
+	std = New(discardWriter{}, 64, 4096)
+	std.SetLevel(SDebug)
+
+	Info("hello")
+
+	cur := std.cur.Load().(*block)
+	got := string(cur.buf[:cur.written])
+	if !strings.Contains(got, "level_test.go") {
+		t.Fatalf("caller info did not point at the call site, got %q", got)
+	}
+	if strings.Contains(got, "alog/level.go") {
+		t.Fatalf("caller info pointed at level.go instead of the call site: %q", got)
+	}
+}
+
+// TestVerboseUsesOwningLogger guards against Verbose.Info/Infof hardcoding
+// std.logLeveled regardless of which *ALogger produced the Verbose value.
+func TestVerboseUsesOwningLogger(t *testing.T) {
+	old := std
+	defer func() { std = old }()
+	std = New(discardWriter{}, 64, 4096)
+
+	inst := New(discardWriter{}, 64, 4096)
+	inst.SetVerbosity(2)
+
+	inst.V(1).Info("from instance")
+
+	if cur := inst.cur.Load().(*block); cur.written == 0 {
+		t.Fatalf("expected the instance logger to receive the record, got nothing staged")
+	}
+	if cur := std.cur.Load().(*block); cur.written != 0 {
+		t.Fatalf("record leaked into std instead of staying on the instance logger that created the Verbose")
+	}
+}
+
+// TestVModuleBareNameMatchesGoFile guards against the glog-style bare module
+// name in a -vmodule spec (e.g. "file=2") failing to match its source file
+// (e.g. ".../file.go") because filepath.Match requires an exact match.
+func TestVModuleBareNameMatchesGoFile(t *testing.T) {
+	f := &vmoduleFilter{rules: []vrule{{pattern: "file", level: 2}}}
+
+	lvl, ok := f.level("/src/pkg/file.go")
+	if !ok || lvl != 2 {
+		t.Fatalf("level(%q) = %v, %v; want 2, true", "/src/pkg/file.go", lvl, ok)
+	}
+
+	if _, ok := f.level("/src/pkg/other.go"); ok {
+		t.Fatalf("level matched a file it shouldn't have")
+	}
+}
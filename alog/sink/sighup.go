@@ -0,0 +1,32 @@
+package sink
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSIGHUP reopens the active file (see Reopen) every time the process
+// receives SIGHUP, and returns a stop function that undoes the signal
+// registration.
+func (rf *RotatingFile) WatchSIGHUP() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				rf.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
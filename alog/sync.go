@@ -0,0 +1,166 @@
+package alog
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// syncer is implemented by output writers that support durable fsync, most
+// notably `*os.File`.
+type syncer interface {
+	Sync() error
+}
+
+const syncRingSize = 4096
+
+// syncSlot tracks one `PrintSync` caller waiting for the next batched fsync.
+type syncSlot struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+// syncRing is a lock-free multi-producer/single-consumer ring buffer of
+// pending sync slots. Producers (any number of concurrent `PrintSync`
+// callers) claim a slot with a CAS loop; only the flush loop in `Run()`
+// consumes, so draining it needs no synchronization beyond atomics.
+type syncRing struct {
+	slots [syncRingSize]unsafe.Pointer // *syncSlot
+	head  uint64
+	tail  uint64
+}
+
+func (r *syncRing) push() *syncSlot {
+	for {
+		head := atomic.LoadUint64(&r.head)
+		tail := atomic.LoadUint64(&r.tail)
+		if head-tail >= syncRingSize {
+			// Ring is full; the consumer will make progress shortly.
+			runtime.Gosched()
+			continue
+		}
+		if atomic.CompareAndSwapUint64(&r.head, head, head+1) {
+			slot := &syncSlot{}
+			slot.wg.Add(1)
+			atomic.StorePointer(&r.slots[head%syncRingSize], unsafe.Pointer(slot))
+			return slot
+		}
+	}
+}
+
+// collect hands `err` to every slot pushed before `boundary` (a `head` value
+// previously snapshotted by the caller) and wakes it up, leaving any slot
+// pushed at or after `boundary` for a later round since this fsync did not
+// cover it. A slot whose producer has reserved it (bumped `head`) but not
+// yet stored the pointer is still in flight: stop there and leave `tail` at
+// that index rather than skipping over it, or the next `collect` round
+// would race past it and the slot's `wg.Done()` would never fire.
+func (r *syncRing) collect(err error, boundary uint64) {
+	tail := atomic.LoadUint64(&r.tail)
+	for ; tail < boundary; tail++ {
+		p := atomic.SwapPointer(&r.slots[tail%syncRingSize], nil)
+		if p == nil {
+			break
+		}
+		slot := (*syncSlot)(p)
+		slot.err = err
+		slot.wg.Done()
+	}
+	atomic.StoreUint64(&r.tail, tail)
+}
+
+// forceRotate publishes the current block to the flusher even if it isn't
+// full yet, so that whatever has been staged so far is on its way to disk.
+// An overflowed block (written past len(buf)) is left to the producer that
+// overflowed it, which rotates it itself.
+func (al *ALogger) forceRotate() {
+	al.rotMu.Lock()
+	cur := al.cur.Load().(*block)
+	used := atomic.LoadInt32(&cur.written)
+	if used > 0 && used <= int32(len(cur.buf)) {
+		waitCommitted(cur, used)
+		al.cur.Store(blockPool.Get().(*block))
+		al.flushCh <- cur
+	}
+	al.rotMu.Unlock()
+}
+
+// drainFlushCh flushes every block and oversized record currently queued
+// for the background flusher without blocking for more to arrive.
+func (al *ALogger) drainFlushCh() {
+	for {
+		select {
+		case bl := <-al.flushCh:
+			al.flushBlock(bl)
+		case b := <-al.oversizedCh:
+			al.flushOversized(b)
+		default:
+			return
+		}
+	}
+}
+
+// doSync forces out whatever has been staged, flushes the output buffer,
+// calls `Sync()` on the underlying writer if supported, and wakes every
+// `PrintSync` caller that was waiting on this round.
+//
+// The ring `head` is snapshotted while `syncMu` is still held, right after
+// `forceRotate`: PrintSync only pushes its slot once its record is staged,
+// also under `syncMu`, so this boundary is exactly "every slot whose record
+// made it into the block `forceRotate` just handed to the flusher". A
+// PrintSync that races in after the snapshot lands in the block `doSync`
+// didn't rotate, and must wait for a later round's fsync to cover it —
+// collect must not complete it early just because it shares this round.
+func (al *ALogger) doSync() {
+	al.syncMu.Lock()
+	al.forceRotate()
+	boundary := atomic.LoadUint64(&al.ring.head)
+	al.syncMu.Unlock()
+
+	al.drainFlushCh()
+	al.buff.Flush()
+
+	var err error
+	if sy, ok := al.output.(syncer); ok {
+		err = sy.Sync()
+	}
+	al.ring.collect(err, boundary)
+	al.signalDrained()
+}
+
+// PrintSync logs a message like `Print`, then blocks until it has been
+// durably written: flushed to the output buffer and, if the output supports
+// it, `fsync`'d. Concurrent `PrintSync` calls coalesce onto a single fsync.
+func (al *ALogger) PrintSync(s ...any) error {
+	al.syncMu.Lock()
+	al.stage([]byte(fmt.Sprint(s...)))
+	slot := al.ring.push()
+	al.syncMu.Unlock()
+
+	select {
+	case al.syncCh <- struct{}{}:
+	default:
+	}
+	slot.wg.Wait()
+	return slot.err
+}
+
+// Sync blocks until everything staged so far has been durably written,
+// without logging a new message.
+func (al *ALogger) Sync() error {
+	al.syncMu.Lock()
+	slot := al.ring.push()
+	al.syncMu.Unlock()
+
+	select {
+	case al.syncCh <- struct{}{}:
+	default:
+	}
+	slot.wg.Wait()
+	return slot.err
+}
+
+func PrintSync(s ...any) error { return std.PrintSync(s...) }
+func Sync() error              { return std.Sync() }
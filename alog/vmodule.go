@@ -0,0 +1,156 @@
+package alog
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Verbose is returned by `V` and reports whether logging at that verbosity
+// level is enabled for the calling file, glog-style. It carries the
+// `*ALogger` it was created from so `v.Info`/`v.Infof` log to that instance
+// rather than the package-global `std`:
+//
+//	if v := alog.V(2); v.Enabled() {
+//		v.Info("expensive detail: ", computeDetail())
+//	}
+type Verbose struct {
+	al *ALogger
+	ok bool
+}
+
+// Enabled reports whether this verbosity level was enabled at the time `V`
+// was called.
+func (v Verbose) Enabled() bool { return v.ok }
+
+// vrule is one `pattern=level` entry from a `-vmodule` spec.
+type vrule struct {
+	pattern string // may contain `*` glob segments, matched against the caller's file path
+	level   int32
+}
+
+type vmoduleFilter struct {
+	rules []vrule
+}
+
+func (f *vmoduleFilter) level(file string) (level int32, ok bool) {
+	if f == nil {
+		return 0, false
+	}
+	file = filepath.ToSlash(file)
+	for _, r := range f.rules {
+		if ok, _ := filepath.Match(r.pattern, file); ok {
+			return r.level, true
+		}
+		// also match against the bare file name, e.g. "file.go=2"
+		base := filepath.Base(file)
+		if ok, _ := filepath.Match(r.pattern, base); ok {
+			return r.level, true
+		}
+		// and against the module name with the ".go" extension stripped,
+		// glog-style, e.g. "file=2" matching "file.go"
+		if ok, _ := filepath.Match(r.pattern, strings.TrimSuffix(base, filepath.Ext(base))); ok {
+			return r.level, true
+		}
+		// a pattern with directories, e.g. "pkg/*=3", is matched against as
+		// many trailing path segments as it has
+		if n := strings.Count(r.pattern, "/") + 1; n > 1 {
+			segs := strings.Split(file, "/")
+			if len(segs) >= n {
+				suffix := strings.Join(segs[len(segs)-n:], "/")
+				if ok, _ := filepath.Match(r.pattern, suffix); ok {
+					return r.level, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// SetVModule parses a glog-style spec such as "file=2,pkg/*=3" and installs
+// it as the per-file verbosity filter. Patterns are matched against the
+// `runtime.Caller` file path (and its base name) of the `V` call site.
+func (al *ALogger) SetVModule(spec string) error {
+	f := &vmoduleFilter{}
+	if spec == "" {
+		al.vmod.Store(f)
+		return nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("alog: invalid vmodule entry %q", entry)
+		}
+		lvl, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("alog: invalid vmodule level in %q: %w", entry, err)
+		}
+		f.rules = append(f.rules, vrule{pattern: parts[0], level: int32(lvl)})
+	}
+	al.vmod.Store(f)
+	return nil
+}
+
+func (al *ALogger) vmodule() *vmoduleFilter {
+	f, _ := al.vmod.Load().(*vmoduleFilter)
+	return f
+}
+
+// verbosity returns the globally configured `V` threshold.
+func (al *ALogger) verbosity() int32 {
+	return atomic.LoadInt32(&al.vlevel)
+}
+
+// SetVerbosity sets the default `V` threshold used when no `-vmodule` rule
+// matches the caller's file.
+func (al *ALogger) SetVerbosity(level int32) {
+	atomic.StoreInt32(&al.vlevel, level)
+}
+
+// V reports whether verbosity level `level` is enabled for the caller,
+// taking any `-vmodule` override for the caller's file into account.
+func (al *ALogger) V(level int32) Verbose {
+	threshold := al.verbosity()
+	if _, file, _, ok := runtime.Caller(1); ok {
+		if lvl, has := al.vmodule().level(file); has {
+			threshold = lvl
+		}
+	}
+	return Verbose{al: al, ok: level <= threshold}
+}
+
+func (v Verbose) Info(s ...any) {
+	if v.ok {
+		v.al.logLeveled(SInfo, 3, fmt.Sprint(s...))
+	}
+}
+
+func (v Verbose) Infof(format string, s ...any) {
+	if v.ok {
+		v.al.logLeveled(SInfo, 3, fmt.Sprintf(format, s...))
+	}
+}
+
+// SetVModule parses and installs a `-vmodule`-style spec on `std`.
+func SetVModule(spec string) error { return std.SetVModule(spec) }
+
+// SetVerbosity sets the default `V` threshold on `std`.
+func SetVerbosity(level int32) { std.SetVerbosity(level) }
+
+// V reports whether verbosity level `level` is enabled on `std` for the caller.
+func V(level int32) Verbose {
+	threshold := std.verbosity()
+	if _, file, _, ok := runtime.Caller(1); ok {
+		if lvl, has := std.vmodule().level(file); has {
+			threshold = lvl
+		}
+	}
+	return Verbose{al: std, ok: level <= threshold}
+}
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"log"
 	"most_logger/alog"
+	"most_logger/alog/sink"
 	"most_logger/thread"
-	"os"
 	"sync"
 	"time"
 )
@@ -26,23 +26,20 @@ func init() {
 	// bufferSize = 100
 
 	f := getLogFile("most.log")
+	f.WatchSIGHUP()
 	alog.Init(f, recordCount, bufferSize)
 	log.SetOutput(f)
 }
 
-func getLogFile(path string) *os.File {
-	_, err := os.Create(path) // if not called for some reasone OpenFile will err
+func getLogFile(path string) *sink.RotatingFile {
+	f, err := sink.New(sink.Config{
+		Path:       path,
+		MaxBytes:   10 * 1024 * 1024,
+		MaxBackups: 5,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	f, err := os.OpenFile(path, os.O_WRONLY, os.ModeAppend)
-	if err != nil {
-		_, err := os.Create(path)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	}
 	return f
 }
 